@@ -0,0 +1,94 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/go-p5/go-p5.github.io/render"
+)
+
+// buildCacheDir returns the directory built WASM binaries are cached under,
+// keyed by content hash.
+func buildCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-p5-gen"), nil
+}
+
+// buildCacheKey hashes everything that determines an example's compiled
+// output: the Go source files in its own directory, the go-p5/p5 revision
+// they were checked out at (which pins the rest of the checkout they import
+// from), and the Go toolchain version used to build them.
+func buildCacheKey(sources []render.SourceFile, revision, goVersion string) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "revision=%s\ngo=%s\n", revision, goVersion)
+	for _, s := range sources {
+		fmt.Fprintf(h, "file=%s\n%s\n", s.Name, s.Content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// buildCacheLookup returns the cached WASM blob for key, if any.
+func buildCacheLookup(dir, key string) ([]byte, bool) {
+	data, err := os.ReadFile(filepath.Join(dir, key+".wasm"))
+	if err != nil {
+		return nil, false
+	}
+	return data, true
+}
+
+// buildCacheStore saves a built WASM blob under key for later reuse.
+func buildCacheStore(dir, key string, wasm []byte) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, key+".wasm"), wasm, 0644)
+}
+
+// buildRecord summarizes a single example build, for build-report.json.
+type buildRecord struct {
+	Name     string `json:"name"`
+	Duration string `json:"duration"`
+	CacheHit bool   `json:"cache_hit"`
+	Size     int64  `json:"size"`
+	Error    string `json:"error,omitempty"`
+}
+
+// writeBuildReport writes the collected build records to path, sorted by
+// name so the report is stable across runs.
+func writeBuildReport(path string, records []buildRecord) error {
+	sort.Slice(records, func(i, j int) bool { return records[i].Name < records[j].Name })
+
+	raw, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, raw, 0644)
+}
+
+// newBuildRecord fills in a buildRecord's duration and size from a build
+// that ran between start and now.
+func newBuildRecord(name string, start time.Time, cacheHit bool, wasm []byte, buildErr error) buildRecord {
+	rec := buildRecord{
+		Name:     name,
+		Duration: time.Since(start).String(),
+		CacheHit: cacheHit,
+		Size:     int64(len(wasm)),
+	}
+	if buildErr != nil {
+		rec.Error = buildErr.Error()
+	}
+	return rec
+}
@@ -0,0 +1,33 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"testing"
+
+	"github.com/go-p5/go-p5.github.io/render"
+)
+
+func TestBuildCacheKey(t *testing.T) {
+	sources := []render.SourceFile{{Name: "main.go", Content: "package main"}}
+
+	key := buildCacheKey(sources, "v1.2.3", "1.21.5")
+	if key != buildCacheKey(sources, "v1.2.3", "1.21.5") {
+		t.Fatalf("buildCacheKey is not deterministic for identical inputs")
+	}
+
+	variants := []func() string{
+		func() string { return buildCacheKey(sources, "v1.2.4", "1.21.5") },
+		func() string { return buildCacheKey(sources, "v1.2.3", "1.22.3") },
+		func() string {
+			return buildCacheKey([]render.SourceFile{{Name: "main.go", Content: "package main // edited"}}, "v1.2.3", "1.21.5")
+		},
+	}
+	for _, variant := range variants {
+		if got := variant(); got == key {
+			t.Fatalf("variant key %q collided with the base key %q", got, key)
+		}
+	}
+}
@@ -13,17 +13,53 @@ import (
 	"path/filepath"
 	"runtime"
 	"strings"
+	"sync"
+	"time"
+
+	"github.com/go-p5/go-p5.github.io/render"
 )
 
 func main() {
 	log.SetPrefix("gen-p5: ")
 	log.SetFlags(0)
 
+	if len(os.Args) > 1 && os.Args[1] == "serve" {
+		serveCmd(os.Args[2:])
+		return
+	}
+
 	vers := flag.String("vers", "main", "version of go-p5/p5 to generate examples for")
+	goVers := flag.String("go", "", "comma-separated list of Go toolchains to build examples with (e.g. 1.21.5,1.22.3); empty uses the system go toolchain")
+	goSHAs := flag.String("go-sha256", "", "path to a JSON file mapping Go toolchain version to its SHA-256 archive checksum, required when -go is set")
+	goCacheMaxAge := flag.Duration("go-cache-max-age", 30*24*time.Hour, "purge cached Go toolchains older than this on each run")
+	renderers := flag.String("renderer", "basic", "comma-separated list of output backends to generate the site with: basic, rich, feed, or all")
+	jobs := flag.Int("jobs", runtime.NumCPU(), "number of examples to build in parallel")
 
 	flag.Parse()
 
-	gen(*vers)
+	gen(*vers, *goVers, *goSHAs, *goCacheMaxAge, *renderers, *jobs)
+}
+
+// resolveRenderers turns a "-renderer" flag value into the concrete
+// render.Renderer implementations to run.
+func resolveRenderers(flagVal string) ([]render.Renderer, error) {
+	names := splitCSV(flagVal)
+	if len(names) == 1 && names[0] == "all" {
+		names = make([]string, 0, len(render.ByName))
+		for name := range render.ByName {
+			names = append(names, name)
+		}
+	}
+
+	var rs []render.Renderer
+	for _, name := range names {
+		r, ok := render.ByName[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown renderer %q", name)
+		}
+		rs = append(rs, r)
+	}
+	return rs, nil
 }
 
 var excludes = map[string]struct{}{
@@ -31,7 +67,29 @@ var excludes = map[string]struct{}{
 	"wasm-p5-ex": {},
 }
 
-func gen(vers string) {
+func gen(vers, goVers, goSHAs string, goCacheMaxAge time.Duration, rendererFlag string, jobs int) {
+	if jobs < 1 {
+		log.Fatalf("-jobs must be at least 1, got %d", jobs)
+	}
+
+	cacheDir, err := defaultToolchainCacheDir()
+	if err != nil {
+		log.Fatalf("could not locate toolchain cache dir: %+v", err)
+	}
+	if err := purgeToolchains(cacheDir, goCacheMaxAge); err != nil {
+		log.Fatalf("could not purge stale toolchains: %+v", err)
+	}
+
+	toolchains, err := resolveToolchains(goVers, goSHAs, cacheDir)
+	if err != nil {
+		log.Fatalf("could not resolve Go toolchains: %+v", err)
+	}
+
+	renderers, err := resolveRenderers(rendererFlag)
+	if err != nil {
+		log.Fatalf("could not resolve renderers: %+v", err)
+	}
+
 	tmp, err := os.MkdirTemp("", "go-p5-gen-")
 	if err != nil {
 		log.Fatalf("could not create tmp dir: %+v", err)
@@ -54,8 +112,10 @@ func gen(vers string) {
 	}
 	log.Printf("revision: %q", revision)
 
-	root := new(strings.Builder)
-	root.WriteString(fmt.Sprintf(rootHeader, revision))
+	commitDate, err := fetchCommitDate(filepath.Join(tmp, "p5"))
+	if err != nil {
+		log.Fatalf("could not retrieve commit date: %+v", err)
+	}
 
 	js, err := loadWASM()
 	if err != nil {
@@ -79,73 +139,342 @@ func gen(vers string) {
 		log.Printf(">>> %+v", p.Name())
 	}
 
+	var goVersions []string
+	for _, tc := range toolchains {
+		goVersions = append(goVersions, tc.version)
+	}
+
+	wasmCacheDir, err := buildCacheDir()
+	if err != nil {
+		log.Fatalf("could not locate build cache dir: %+v", err)
+	}
+
+	var names []string
 	for _, dir := range pkgs {
 		if _, ok := excludes[dir.Name()]; ok {
 			log.Printf("ignoring %s...", dir.Name())
 			continue
 		}
-		name := "example/" + dir.Name()
-		log.Printf("generating %s...", name)
-		pkg := filepath.Base(name)
-		cmd := exec.Command("go", "build", "-o", "../bin/"+pkg+".wasm", "./"+name)
-		cmd.Dir = filepath.Join(tmp, "p5")
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-		cmd.Env = []string{
-			"GOBIN=" + tmp + "/bin",
-			"GOOS=js",
-			"GOARCH=wasm",
-		}
-		cmd.Env = append(cmd.Env, os.Environ()...)
-
-		err = cmd.Run()
-		if err != nil {
-			log.Fatalf("could not build WASM %q: %+v", name, err)
+		names = append(names, dir.Name())
+	}
+
+	examples := make([]*render.Example, len(names))
+	var records []buildRecord
+	var recordsMu, gitMu sync.Mutex
+	sem := make(chan struct{}, jobs)
+	var wg sync.WaitGroup
+
+	for i, pkgName := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(i int, pkgName string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			ex, recs := buildPkg(buildPkgArgs{
+				tmp: tmp, pkgName: pkgName, revision: revision,
+				toolchains: toolchains, goVersions: goVersions,
+				renderers: renderers, cacheDir: wasmCacheDir, gitMu: &gitMu,
+			})
+			examples[i] = ex
+
+			recordsMu.Lock()
+			records = append(records, recs...)
+			recordsMu.Unlock()
+		}(i, pkgName)
+	}
+	wg.Wait()
+
+	if err := writeBuildReport("build-report.json", records); err != nil {
+		log.Fatalf("could not write build report: %+v", err)
+	}
+
+	var siteExamples []render.Example
+	var failed []string
+	for _, ex := range examples {
+		if ex != nil {
+			siteExamples = append(siteExamples, *ex)
 		}
+	}
+	for _, rec := range records {
+		if rec.Error != "" {
+			failed = append(failed, fmt.Sprintf("%s: %s", rec.Name, rec.Error))
+		}
+	}
 
-		err = os.MkdirAll(name, 0755)
+	site := render.Site{Revision: revision, CommitDate: commitDate, Examples: siteExamples}
+	if err := writeSitePages(renderers, site); err != nil {
+		log.Fatalf("could not write site pages: %+v", err)
+	}
+
+	if len(failed) > 0 {
+		log.Fatalf("%d example(s) failed to build:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+}
+
+type buildPkgArgs struct {
+	tmp        string
+	pkgName    string
+	revision   string
+	toolchains []toolchain
+	goVersions []string
+	renderers  []render.Renderer
+	cacheDir   string
+	gitMu      *sync.Mutex
+}
+
+// buildPkg builds every configured Go toolchain variant of one example,
+// serving built WASM blobs from the content-addressed cache when possible,
+// and writes its generated pages. It never aborts the whole run: a failed
+// toolchain build is recorded in its buildRecord and skipped.
+func buildPkg(a buildPkgArgs) (*render.Example, []buildRecord) {
+	name := "example/" + a.pkgName
+	log.Printf("generating %s...", name)
+
+	sources, err := readSources(filepath.Join(a.tmp, "p5", name))
+	if err != nil {
+		return nil, []buildRecord{{Name: a.pkgName, Error: err.Error()}}
+	}
+
+	var records []buildRecord
+	var defaultEx *render.Example
+	var defaultVersion string
+
+	for _, tc := range a.toolchains {
+		start := time.Now()
+
+		outDir := name
+		src := fmt.Sprintf("https://go-p5.github.io/example/%s/%s.wasm", a.pkgName, a.pkgName)
+		if tc.version != "" {
+			outDir = filepath.Join(name, tc.version)
+			src = fmt.Sprintf("https://go-p5.github.io/example/%s/%s/%s.wasm", a.pkgName, tc.version, a.pkgName)
+		}
+
+		key := buildCacheKey(sources, a.revision, tc.version)
+		wasm, hit := buildCacheLookup(a.cacheDir, key)
+		if !hit {
+			wasm, err = buildWASM(a.tmp, a.pkgName, name, tc)
+			if err != nil {
+				records = append(records, newBuildRecord(name+"@go"+tc.version, start, false, nil, err))
+				continue
+			}
+			if err := buildCacheStore(a.cacheDir, key, wasm); err != nil {
+				log.Printf("could not cache build for %q: %+v", name, err)
+			}
+		}
+
+		ex := render.Example{
+			Pkg:              a.pkgName,
+			Title:            "Go-P5: " + a.pkgName,
+			WASMSrc:          src,
+			GoVersions:       a.goVersions,
+			CurrentGoVersion: tc.version,
+			Sources:          sources,
+		}
+
+		if err := writeExamplePages(a.renderers, ex, outDir); err != nil {
+			records = append(records, newBuildRecord(name+"@go"+tc.version, start, hit, wasm, err))
+			continue
+		}
+
+		fname := filepath.Join(outDir, a.pkgName+".wasm")
+		if err := os.WriteFile(fname, wasm, 0644); err != nil {
+			records = append(records, newBuildRecord(name+"@go"+tc.version, start, hit, wasm, err))
+			continue
+		}
+
+		a.gitMu.Lock()
+		err = exec.Command("git", "add", fname).Run()
+		a.gitMu.Unlock()
 		if err != nil {
-			log.Fatalf("could not create source dir %q: %+v", name, err)
+			records = append(records, newBuildRecord(name+"@go"+tc.version, start, hit, wasm, err))
+			continue
+		}
+
+		records = append(records, newBuildRecord(name+"@go"+tc.version, start, hit, wasm, nil))
+		if defaultEx == nil {
+			// The canonical build: the first toolchain that actually built
+			// successfully, not necessarily a.toolchains[0] (which may have
+			// failed while a later toolchain succeeded).
+			defaultEx = &ex
+			defaultVersion = tc.version
 		}
+	}
 
-		title := "Go-P5: " + pkg
-		src := fmt.Sprintf("https://go-p5.github.io/example/%s/%s.wasm", pkg, pkg)
-		err = os.WriteFile(
-			filepath.Join(name, "index.html"),
-			[]byte(fmt.Sprintf(indexHTML, title, src)),
-			0644,
-		)
+	if defaultVersion != "" && defaultEx != nil {
+		// Keep every renderer's canonical example/<pkg>/ URL working by
+		// mirroring the canonical build there, alongside the selector. This
+		// runs for a single custom toolchain too, since that also leaves the
+		// canonical path unwritten.
+		def := filepath.Join(name, defaultVersion)
+		prefixes, err := rendererPrefixes(a.renderers, *defaultEx)
 		if err != nil {
-			log.Fatalf("could not write example HTML %q: %+v", name, err)
+			records = append(records, buildRecord{Name: name, Error: fmt.Sprintf("could not determine renderer output prefixes: %+v", err)})
 		}
+		for _, prefix := range prefixes {
+			if err := copyDir(filepath.Join(prefix, def), filepath.Join(prefix, name)); err != nil {
+				records = append(records, buildRecord{Name: name, Error: fmt.Sprintf("could not mirror default toolchain build under %q: %+v", prefix, err)})
+			}
+		}
+	}
 
-		fname := filepath.Join(name, pkg+".wasm")
-		wasm, err := os.ReadFile(filepath.Join(tmp, "bin", pkg+".wasm"))
+	return defaultEx, records
+}
+
+// rendererPrefixes returns the distinct output-path prefixes (e.g. "" for
+// basic, "rich/" for rich) that the configured renderers place ex's page
+// under, so the default-toolchain mirror step can cover every one of them,
+// not just the bare example/<pkg>/ path.
+func rendererPrefixes(renderers []render.Renderer, ex render.Example) ([]string, error) {
+	site := render.Site{Examples: []render.Example{ex}}
+	suffix := filepath.Join("example", ex.Pkg, "index.html")
+
+	seen := map[string]struct{}{}
+	var prefixes []string
+	for _, r := range renderers {
+		files, err := r.Render(site)
 		if err != nil {
-			log.Fatalf("could not read WASM binary %q: %+v", name, err)
+			return nil, fmt.Errorf("renderer %q: %w", r.Name(), err)
+		}
+		for name := range files {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			prefix := strings.TrimSuffix(name, suffix)
+			if _, ok := seen[prefix]; ok {
+				continue
+			}
+			seen[prefix] = struct{}{}
+			prefixes = append(prefixes, prefix)
 		}
-		err = os.WriteFile(fname, wasm, 0644)
+	}
+	return prefixes, nil
+}
+
+// buildWASM compiles one example with the given toolchain and returns the
+// resulting WASM binary.
+func buildWASM(tmp, pkg, name string, tc toolchain) ([]byte, error) {
+	goBin := "go"
+	if tc.goBin != "" {
+		goBin = tc.goBin
+	}
+
+	out := filepath.Join(tmp, "bin", pkg+"-"+sanitize(tc.version)+".wasm")
+	cmd := exec.Command(goBin, "build", "-o", out, "./"+name)
+	cmd.Dir = filepath.Join(tmp, "p5")
+	cmd.Env = append([]string{"GOOS=js", "GOARCH=wasm"}, os.Environ()...)
+
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("could not build WASM %q with go%s: %w\n%s", name, tc.version, err, output)
+	}
+	return os.ReadFile(out)
+}
+
+func sanitize(s string) string {
+	if s == "" {
+		return "system"
+	}
+	return strings.ReplaceAll(s, ".", "_")
+}
+
+// writeExamplePages asks each renderer for the page(s) it produces for a
+// single example, and writes the per-example page(s) among them under
+// outDir. Site-wide files (root indexes, feeds, sitemaps) are skipped here;
+// writeSitePages handles those once, across every example.
+func writeExamplePages(renderers []render.Renderer, ex render.Example, outDir string) error {
+	site := render.Site{Examples: []render.Example{ex}}
+	for _, r := range renderers {
+		files, err := r.Render(site)
 		if err != nil {
-			log.Fatalf("could not write WASM binary %q: %+v", name, err)
+			return fmt.Errorf("renderer %q: %w", r.Name(), err)
 		}
+		suffix := filepath.Join("example", ex.Pkg, "index.html")
+		for name, content := range files {
+			if !strings.HasSuffix(name, suffix) {
+				continue
+			}
+			prefix := strings.TrimSuffix(name, suffix)
+			dir := filepath.Join(prefix, outDir)
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return err
+			}
+			if err := os.WriteFile(filepath.Join(dir, "index.html"), content, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		err = exec.Command("git", "add", fname).Run()
+// writeSitePages asks each renderer for the site-wide files it produces
+// (root indexes, Atom feeds, sitemaps) and writes them to the current
+// directory.
+func writeSitePages(renderers []render.Renderer, site render.Site) error {
+	for _, r := range renderers {
+		files, err := r.Render(site)
 		if err != nil {
-			log.Fatalf("could not add WASM binary to repository: %+v", err)
+			return fmt.Errorf("renderer %q: %w", r.Name(), err)
 		}
+		for name, content := range files {
+			if strings.HasPrefix(name, "example/") || strings.Contains(name, "/example/") {
+				continue
+			}
+			if dir := filepath.Dir(name); dir != "." {
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					return err
+				}
+			}
+			if err := os.WriteFile(name, content, 0644); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
 
-		root.WriteString(fmt.Sprintf(
-			"<li><a href=%q>%s</a></li>\n",
-			"https://go-p5.github.io/example/"+pkg+"/index.html",
-			pkg,
-		))
+// readSources reads every .go file directly under dir, for renderers that
+// show an example's source alongside its running WASM binary.
+func readSources(dir string) ([]render.SourceFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
 	}
+	var sources []render.SourceFile
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".go" {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(dir, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		sources = append(sources, render.SourceFile{Name: e.Name(), Content: string(content)})
+	}
+	return sources, nil
+}
 
-	root.WriteString(rootFooter)
-	err = os.WriteFile("index.html", []byte(root.String()), 0644)
+// copyDir copies the regular files directly under src into dst.
+func copyDir(src, dst string) error {
+	entries, err := os.ReadDir(src)
 	if err != nil {
-		log.Fatalf("could not create root index: %+v", err)
+		return err
 	}
+	if err := os.MkdirAll(dst, 0755); err != nil {
+		return err
+	}
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(src, e.Name()))
+		if err != nil {
+			return err
+		}
+		if err := os.WriteFile(filepath.Join(dst, e.Name()), data, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func loadWASM() (js []byte, err error) {
@@ -157,83 +486,21 @@ func loadWASM() (js []byte, err error) {
 	return
 }
 
-const indexHTML = `
-<!doctype html>
-<!--
-Copyright 2018 The Go Authors. All rights reserved.
-Use of this source code is governed by a BSD-style
-license that can be found in the LICENSE file.
--->
-<html>
-
-<head>
-        <meta charset="utf-8">
-        <title>%s</title>
-</head>
-
-<body>
-        <!--
-        Add the following polyfill for Microsoft Edge 17/18 support:
-        <script src="https://cdn.jsdelivr.net/npm/text-encoding@0.7.0/lib/encoding.min.js"></script>
-        (see https://caniuse.com/#feat=textencoder)
-        -->
-		<script src="https://go-p5.github.io/assets/wasm_exec.js"></script>
-        <script>
-                if (!WebAssembly.instantiateStreaming) { // polyfill
-                        WebAssembly.instantiateStreaming = async (resp, importObject) => {
-                                const source = await (await resp).arrayBuffer();
-                                return await WebAssembly.instantiate(source, importObject);
-                        };
-                }
-
-                const go = new Go();
-                let mod, inst;
-                WebAssembly.instantiateStreaming(fetch("%s"), go.importObject).then((result) => {
-                        mod = result.module;
-                        inst = result.instance;
-                        document.getElementById("runButton").disabled = false;
-                }).catch((err) => {
-                        console.error(err);
-                });
-
-                async function run() {
-                        console.clear();
-                        await go.run(inst);
-                        inst = await WebAssembly.instantiate(mod, go.importObject); // reset instance
-                }
-        </script>
-
-        <button onClick="run();" id="runButton" disabled>Run</button>
-</body>
-
-</html>
-`
-
-const rootHeader = `
-<!doctype html>
-<html>
-<head>
-        <meta charset="utf-8">
-        <title>Go-P5</title>
-</head>
-
-<body>
-<h2>Welcome to the Go-P5 examples page (version=%s)</h2>
-This page shows a few <code>go-p5</code> examples, compiled to <code>WASM</code>.
-
-<ul>
-`
-
-const rootFooter = `
-</ul>
-</body>
-
-</html>
-`
-
 func fetchRevision(dir string) (string, error) {
 	cmd := exec.Command("git", "describe", "--tags", "--always")
 	cmd.Dir = dir
 	out, err := cmd.CombinedOutput()
 	return strings.TrimSpace(string(out)), err
 }
+
+// fetchCommitDate returns the commit date of dir's HEAD, used to key the
+// feed and sitemap renderers' entries.
+func fetchCommitDate(dir string) (time.Time, error) {
+	cmd := exec.Command("git", "log", "-1", "--format=%cI")
+	cmd.Dir = dir
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%s: %w", out, err)
+	}
+	return time.Parse(time.RFC3339, strings.TrimSpace(string(out)))
+}
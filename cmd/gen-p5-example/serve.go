@@ -0,0 +1,343 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-p5/go-p5.github.io/render"
+)
+
+// serveCmd implements the "serve" subcommand: it starts a local HTTP server
+// that watches a p5/example tree for changes, rebuilds the affected example
+// WASM binary on write, and notifies open browser tabs so they can reload
+// themselves.
+func serveCmd(args []string) {
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	addr := fs.String("addr", "localhost:8000", "address to serve the examples on")
+	path := fs.String("path", "", "local path to a checkout of go-p5/p5 (cloned if empty)")
+	poll := fs.Duration("poll", 500*time.Millisecond, "how often to poll the example tree for changes")
+	_ = fs.Parse(args)
+
+	root := *path
+	if root == "" {
+		tmp, err := os.MkdirTemp("", "go-p5-gen-")
+		if err != nil {
+			log.Fatalf("could not create tmp dir: %+v", err)
+		}
+		cmd := exec.Command("git", "clone", "--depth=1", "https://github.com/go-p5/p5")
+		cmd.Dir = tmp
+		cmd.Stdout = os.Stdout
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			log.Fatalf("could not clone p5: %+v", err)
+		}
+		root = filepath.Join(tmp, "p5")
+	}
+
+	srv := newWatchServer(root)
+	if err := srv.rebuildAll(); err != nil {
+		log.Fatalf("could not build examples: %+v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/events", srv.events)
+	mux.HandleFunc("/build", srv.build)
+	mux.Handle("/", http.FileServer(http.Dir(".")))
+
+	httpSrv := &http.Server{Addr: *addr, Handler: mux}
+
+	go srv.watch(*poll)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go func() {
+		log.Printf("serving examples on http://%s", *addr)
+		if err := httpSrv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Fatalf("could not serve: %+v", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Printf("shutting down...")
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := httpSrv.Shutdown(shutdownCtx); err != nil {
+		log.Fatalf("could not shut down cleanly: %+v", err)
+	}
+}
+
+// watchServer rebuilds example packages on change and notifies connected
+// browser tabs over Server-Sent Events.
+type watchServer struct {
+	root   string
+	mtimes map[string]time.Time
+
+	mu      sync.Mutex
+	clients map[chan string]struct{}
+
+	buildMu sync.Mutex // serializes /build requests, which edit files in place
+}
+
+func newWatchServer(root string) *watchServer {
+	return &watchServer{
+		root:    root,
+		mtimes:  make(map[string]time.Time),
+		clients: make(map[chan string]struct{}),
+	}
+}
+
+func (srv *watchServer) rebuildAll() error {
+	pkgs, err := os.ReadDir(filepath.Join(srv.root, "example"))
+	if err != nil {
+		return fmt.Errorf("could not read example dir: %w", err)
+	}
+	for _, dir := range pkgs {
+		if _, ok := excludes[dir.Name()]; ok {
+			continue
+		}
+		if err := srv.rebuild(dir.Name()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// watch polls the example tree every interval and rebuilds any package whose
+// sources changed since the last scan.
+func (srv *watchServer) watch(interval time.Duration) {
+	for range time.Tick(interval) {
+		pkgs, err := os.ReadDir(filepath.Join(srv.root, "example"))
+		if err != nil {
+			log.Printf("could not read example dir: %+v", err)
+			continue
+		}
+		for _, dir := range pkgs {
+			if _, ok := excludes[dir.Name()]; ok {
+				continue
+			}
+			changed, err := srv.changed(dir.Name())
+			if err != nil {
+				log.Printf("could not stat %q: %+v", dir.Name(), err)
+				continue
+			}
+			if !changed {
+				continue
+			}
+			log.Printf("rebuilding %s...", dir.Name())
+			if err := srv.rebuild(dir.Name()); err != nil {
+				log.Printf("could not rebuild %q: %+v", dir.Name(), err)
+				continue
+			}
+			srv.broadcast("reload")
+		}
+	}
+}
+
+func (srv *watchServer) changed(pkg string) (bool, error) {
+	dir := filepath.Join(srv.root, "example", pkg)
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, err
+	}
+	changed := false
+	for _, e := range entries {
+		if e.IsDir() || filepath.Ext(e.Name()) != ".go" {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return false, err
+		}
+		key := filepath.Join(dir, e.Name())
+		if prev, ok := srv.mtimes[key]; !ok || info.ModTime().After(prev) {
+			srv.mtimes[key] = info.ModTime()
+			changed = true
+		}
+	}
+	return changed, nil
+}
+
+func (srv *watchServer) rebuild(pkg string) error {
+	name := "example/" + pkg
+	out := filepath.Join(srv.root, "bin", pkg+".wasm")
+
+	cmd := exec.Command("go", "build", "-o", out, "./"+name)
+	cmd.Dir = srv.root
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	cmd.Env = append([]string{"GOOS=js", "GOARCH=wasm"}, os.Environ()...)
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("could not build WASM %q: %w", name, err)
+	}
+
+	if err := os.MkdirAll(name, 0755); err != nil {
+		return fmt.Errorf("could not create %q: %w", name, err)
+	}
+
+	wasm, err := os.ReadFile(out)
+	if err != nil {
+		return fmt.Errorf("could not read WASM binary %q: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(name, pkg+".wasm"), wasm, 0644); err != nil {
+		return fmt.Errorf("could not write WASM binary %q: %w", name, err)
+	}
+
+	sources, err := readSources(filepath.Join(srv.root, name))
+	if err != nil {
+		return fmt.Errorf("could not read example sources %q: %w", name, err)
+	}
+
+	ex := render.Example{Pkg: pkg, Title: "Go-P5: " + pkg, WASMSrc: pkg + ".wasm", Reload: true, Sources: sources}
+	site := render.Site{Examples: []render.Example{ex}}
+
+	basicFiles, err := (render.Basic{}).Render(site)
+	if err != nil {
+		return fmt.Errorf("could not render example HTML %q: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(name, "index.html"), basicFiles[filepath.Join(name, "index.html")], 0644); err != nil {
+		return fmt.Errorf("could not write example HTML %q: %w", name, err)
+	}
+
+	// Also publish a "Go Playground"-style sandbox page with the example's
+	// source and a live editor wired to /build. It lives under a different
+	// path than the WASM binary, so it needs an absolute reference to it.
+	ex.WASMSrc = "/" + filepath.Join(name, pkg+".wasm")
+	richFiles, err := (render.Rich{}).Render(render.Site{Examples: []render.Example{ex}})
+	if err != nil {
+		return fmt.Errorf("could not render rich HTML %q: %w", name, err)
+	}
+	richPath := filepath.Join("rich", name, "index.html")
+	if err := os.MkdirAll(filepath.Dir(richPath), 0755); err != nil {
+		return fmt.Errorf("could not create %q: %w", filepath.Dir(richPath), err)
+	}
+	if err := os.WriteFile(richPath, richFiles[richPath], 0644); err != nil {
+		return fmt.Errorf("could not write rich HTML %q: %w", richPath, err)
+	}
+
+	return nil
+}
+
+// build is the "Compile & Run" endpoint: it overwrites one source file of
+// an example with the posted content, builds the example to WASM, restores
+// the original file, and streams the resulting binary back for the browser
+// to hot-instantiate.
+func (srv *watchServer) build(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	pkg := r.URL.Query().Get("pkg")
+	file := r.URL.Query().Get("file")
+	if pkg == "" || file == "" || strings.ContainsAny(pkg, "/.") || strings.ContainsAny(file, "/") {
+		http.Error(w, "invalid pkg or file", http.StatusBadRequest)
+		return
+	}
+
+	src, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	srv.buildMu.Lock()
+	defer srv.buildMu.Unlock()
+
+	path := filepath.Join(srv.root, "example", pkg, file)
+	orig, err := os.ReadFile(path)
+	if err != nil {
+		http.Error(w, "unknown example or file", http.StatusNotFound)
+		return
+	}
+	defer os.WriteFile(path, orig, 0644)
+
+	if err := os.WriteFile(path, src, 0644); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	out := filepath.Join(srv.root, "bin", pkg+"-edit.wasm")
+	cmd := exec.Command("go", "build", "-o", out, "./example/"+pkg)
+	cmd.Dir = srv.root
+	cmd.Env = append([]string{"GOOS=js", "GOARCH=wasm"}, os.Environ()...)
+	buildLog, err := cmd.CombinedOutput()
+	if err != nil {
+		w.Header().Set("Content-Type", "text/plain")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		w.Write(buildLog)
+		return
+	}
+	defer os.Remove(out)
+
+	wasm, err := os.ReadFile(out)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/wasm")
+	w.Write(wasm)
+}
+
+// events is the SSE endpoint browser tabs connect to in order to be told
+// when to reload.
+func (srv *watchServer) events(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "streaming unsupported", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+
+	ch := make(chan string, 1)
+	srv.mu.Lock()
+	srv.clients[ch] = struct{}{}
+	srv.mu.Unlock()
+	defer func() {
+		srv.mu.Lock()
+		delete(srv.clients, ch)
+		srv.mu.Unlock()
+	}()
+
+	for {
+		select {
+		case msg := <-ch:
+			fmt.Fprintf(w, "data: %s\n\n", msg)
+			flusher.Flush()
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (srv *watchServer) broadcast(msg string) {
+	srv.mu.Lock()
+	defer srv.mu.Unlock()
+	for ch := range srv.clients {
+		select {
+		case ch <- msg:
+		default:
+		}
+	}
+}
@@ -0,0 +1,239 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// toolchain describes a Go toolchain that examples can be built with.
+type toolchain struct {
+	version string // e.g. "1.21.5", or "" for the system go
+	goBin   string // path to the "go" binary to invoke, empty means use $PATH
+}
+
+// resolveToolchains turns a comma-separated "-go" flag value into the list
+// of toolchains to build examples with, downloading and caching any that are
+// missing. An empty vers yields a single toolchain that uses the system go.
+func resolveToolchains(vers string, shaFile, cacheDir string) ([]toolchain, error) {
+	if vers == "" {
+		return []toolchain{{}}, nil
+	}
+
+	shas, err := loadToolchainSHAs(shaFile)
+	if err != nil {
+		return nil, fmt.Errorf("could not load toolchain checksums: %w", err)
+	}
+
+	if err := os.MkdirAll(cacheDir, 0755); err != nil {
+		return nil, fmt.Errorf("could not create toolchain cache %q: %w", cacheDir, err)
+	}
+
+	var tcs []toolchain
+	for _, v := range splitCSV(vers) {
+		goBin, err := fetchToolchain(v, shas, cacheDir)
+		if err != nil {
+			return nil, fmt.Errorf("could not fetch go%s: %w", v, err)
+		}
+		tcs = append(tcs, toolchain{version: v, goBin: goBin})
+	}
+	return tcs, nil
+}
+
+// defaultToolchainCacheDir returns the directory gen-p5 caches downloaded Go
+// toolchains in.
+func defaultToolchainCacheDir() (string, error) {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "go-p5-gen", "toolchains"), nil
+}
+
+// loadToolchainSHAs reads a JSON file mapping a Go toolchain version to the
+// SHA-256 checksum of its official archive, as published at
+// https://go.dev/dl/. An empty path means no verification is configured.
+func loadToolchainSHAs(path string) (map[string]string, error) {
+	if path == "" {
+		return nil, nil
+	}
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	shas := make(map[string]string)
+	if err := json.Unmarshal(raw, &shas); err != nil {
+		return nil, fmt.Errorf("could not parse %q: %w", path, err)
+	}
+	return shas, nil
+}
+
+// fetchToolchain ensures a Go toolchain for the given version is present
+// under cacheDir, downloading and extracting it if necessary, and returns
+// the path to its "go" binary.
+func fetchToolchain(version string, shas map[string]string, cacheDir string) (string, error) {
+	dir := filepath.Join(cacheDir, version)
+	goBin := filepath.Join(dir, "go", "bin", "go")
+
+	if _, err := os.Stat(goBin); err == nil {
+		touch(dir)
+		return goBin, nil
+	}
+
+	sha, ok := shas[version]
+	if !ok {
+		return "", fmt.Errorf("no SHA-256 checksum configured for go%s", version)
+	}
+
+	url := fmt.Sprintf("https://go.dev/dl/go%s.%s-%s.tar.gz", version, runtime.GOOS, runtime.GOARCH)
+	log.Printf("downloading %s...", url)
+
+	resp, err := http.Get(url)
+	if err != nil {
+		return "", fmt.Errorf("could not download %q: %w", url, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("could not download %q: status %s", url, resp.Status)
+	}
+
+	archive, err := os.CreateTemp("", "go-p5-gen-toolchain-*.tar.gz")
+	if err != nil {
+		return "", fmt.Errorf("could not create temp file: %w", err)
+	}
+	defer os.Remove(archive.Name())
+	defer archive.Close()
+
+	sum := sha256.New()
+	if _, err := io.Copy(io.MultiWriter(archive, sum), resp.Body); err != nil {
+		return "", fmt.Errorf("could not write %q: %w", archive.Name(), err)
+	}
+
+	if got := hex.EncodeToString(sum.Sum(nil)); got != sha {
+		return "", fmt.Errorf("checksum mismatch for go%s: got %s, want %s", version, got, sha)
+	}
+
+	if _, err := archive.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("could not rewind %q: %w", archive.Name(), err)
+	}
+
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("could not create %q: %w", dir, err)
+	}
+	if err := extractTarGz(archive, dir); err != nil {
+		return "", fmt.Errorf("could not extract %q: %w", archive.Name(), err)
+	}
+
+	return goBin, nil
+}
+
+func extractTarGz(r io.Reader, dst string) error {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return err
+	}
+	defer gz.Close()
+
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		path := filepath.Join(dst, hdr.Name)
+		if path != dst && !strings.HasPrefix(path, dst+string(filepath.Separator)) {
+			return fmt.Errorf("tar entry %q escapes %q", hdr.Name, dst)
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, os.FileMode(hdr.Mode))
+			if err != nil {
+				return err
+			}
+			if _, err := io.Copy(f, tr); err != nil {
+				f.Close()
+				return err
+			}
+			f.Close()
+		}
+	}
+}
+
+// touch bumps dir's mtime so purgeToolchains treats it as recently used.
+func touch(dir string) {
+	now := time.Now()
+	_ = os.Chtimes(dir, now, now)
+}
+
+// purgeToolchains removes cached toolchains under cacheDir that have not
+// been used in longer than maxAge, so long-running CI machines don't
+// accumulate Go toolchains forever.
+func purgeToolchains(cacheDir string, maxAge time.Duration) error {
+	entries, err := os.ReadDir(cacheDir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	cutoff := time.Now().Add(-maxAge)
+	for _, e := range entries {
+		if !e.IsDir() {
+			continue
+		}
+		info, err := e.Info()
+		if err != nil {
+			return err
+		}
+		if info.ModTime().Before(cutoff) {
+			dir := filepath.Join(cacheDir, e.Name())
+			log.Printf("purging stale toolchain cache %q...", dir)
+			if err := os.RemoveAll(dir); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func splitCSV(s string) []string {
+	var out []string
+	start := 0
+	for i := 0; i <= len(s); i++ {
+		if i == len(s) || s[i] == ',' {
+			if i > start {
+				out = append(out, s[start:i])
+			}
+			start = i + 1
+		}
+	}
+	return out
+}
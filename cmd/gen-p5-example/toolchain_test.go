@@ -0,0 +1,85 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// makeTarGz builds a gzipped tar archive containing one entry per name,
+// each holding its own name as content.
+func makeTarGz(t *testing.T, names ...string) *bytes.Buffer {
+	t.Helper()
+
+	buf := new(bytes.Buffer)
+	gz := gzip.NewWriter(buf)
+	tw := tar.NewWriter(gz)
+
+	for _, name := range names {
+		hdr := &tar.Header{Name: name, Mode: 0644, Size: int64(len(name))}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("could not write header for %q: %+v", name, err)
+		}
+		if _, err := tw.Write([]byte(name)); err != nil {
+			t.Fatalf("could not write content for %q: %+v", name, err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("could not close tar writer: %+v", err)
+	}
+	if err := gz.Close(); err != nil {
+		t.Fatalf("could not close gzip writer: %+v", err)
+	}
+	return buf
+}
+
+func TestExtractTarGz(t *testing.T) {
+	t.Run("normal entry", func(t *testing.T) {
+		dst := t.TempDir()
+		if err := extractTarGz(makeTarGz(t, "go/bin/go"), dst); err != nil {
+			t.Fatalf("extractTarGz: %+v", err)
+		}
+		if _, err := os.Stat(filepath.Join(dst, "go", "bin", "go")); err != nil {
+			t.Fatalf("extracted file missing: %+v", err)
+		}
+	})
+
+	t.Run("path escaping dst is rejected", func(t *testing.T) {
+		dst := t.TempDir()
+		err := extractTarGz(makeTarGz(t, "../../evil"), dst)
+		if err == nil {
+			t.Fatalf("expected an error for a tar entry escaping %q, got nil", dst)
+		}
+	})
+}
+
+func TestSplitCSV(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []string
+	}{
+		{"", nil},
+		{"1.21.5", []string{"1.21.5"}},
+		{"1.21.5,1.22.3", []string{"1.21.5", "1.22.3"}},
+		{"1.21.5,,1.22.3", []string{"1.21.5", "1.22.3"}},
+	}
+
+	for _, tt := range tests {
+		got := splitCSV(tt.in)
+		if len(got) != len(tt.want) {
+			t.Fatalf("splitCSV(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+		for i := range got {
+			if got[i] != tt.want[i] {
+				t.Fatalf("splitCSV(%q) = %v, want %v", tt.in, got, tt.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,172 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"fmt"
+	"path"
+	"strings"
+)
+
+// Basic is the plain-HTML renderer gen-p5 has always used: a root page
+// linking to one page per example, each running its WASM binary behind a
+// "Run" button.
+type Basic struct{}
+
+func (Basic) Name() string { return "basic" }
+
+func (b Basic) Render(site Site) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+
+	root := new(strings.Builder)
+	root.WriteString(fmt.Sprintf(rootHeader, site.Revision))
+	for _, ex := range site.Examples {
+		root.WriteString(fmt.Sprintf(
+			"<li><a href=%q>%s</a></li>\n",
+			"https://go-p5.github.io/example/"+ex.Pkg+"/index.html",
+			ex.Pkg,
+		))
+
+		out[path.Join("example", ex.Pkg, "index.html")] = []byte(b.renderExample(ex))
+	}
+	root.WriteString(rootFooter)
+	out["index.html"] = []byte(root.String())
+
+	return out, nil
+}
+
+func (Basic) renderExample(ex Example) string {
+	return fmt.Sprintf(indexHTML, ex.Title, ex.WASMSrc, extras(ex, "/example/"+ex.Pkg))
+}
+
+// extras renders the bits spliced into indexHTML below the "Run" button: an
+// optional Go-toolchain selector and an optional live-reload client script.
+// root is the site-rooted path to the example's canonical directory (e.g.
+// "/example/mandelbrot" or "/rich/example/mandelbrot"), used to build
+// version-switcher links that work from any toolchain's subdirectory.
+func extras(ex Example, root string) string {
+	out := new(strings.Builder)
+	if sel := goVersionSelector(root, ex.GoVersions, ex.CurrentGoVersion); sel != "" {
+		out.WriteString(sel)
+	}
+	if ex.Reload {
+		out.WriteString(reloadScript)
+	}
+	return out.String()
+}
+
+// goVersionSelector renders the <select> that lets a reader switch between
+// the WASM binaries built for an example with each configured Go toolchain.
+// It is empty when fewer than two toolchains were used. Options link to
+// root-relative paths, since a reader may be on any toolchain's subdirectory
+// and a plain relative link would resolve beneath the current one instead.
+func goVersionSelector(root string, versions []string, current string) string {
+	if len(versions) <= 1 {
+		return ""
+	}
+
+	opts := new(strings.Builder)
+	for _, v := range versions {
+		selected := ""
+		if v == current {
+			selected = " selected"
+		}
+		fmt.Fprintf(opts, "                        <option value=%q%s>go%s</option>\n", path.Join(root, v), selected, v)
+	}
+
+	return fmt.Sprintf(`
+        <label for="goVersion">Go toolchain:</label>
+        <select id="goVersion" onchange="location = this.value + '/index.html';">
+%s        </select>
+`, opts.String())
+}
+
+const indexHTML = `
+<!doctype html>
+<!--
+Copyright 2018 The Go Authors. All rights reserved.
+Use of this source code is governed by a BSD-style
+license that can be found in the LICENSE file.
+-->
+<html>
+
+<head>
+        <meta charset="utf-8">
+        <title>%s</title>
+</head>
+
+<body>
+        <!--
+        Add the following polyfill for Microsoft Edge 17/18 support:
+        <script src="https://cdn.jsdelivr.net/npm/text-encoding@0.7.0/lib/encoding.min.js"></script>
+        (see https://caniuse.com/#feat=textencoder)
+        -->
+		<script src="https://go-p5.github.io/assets/wasm_exec.js"></script>
+        <script>
+                if (!WebAssembly.instantiateStreaming) { // polyfill
+                        WebAssembly.instantiateStreaming = async (resp, importObject) => {
+                                const source = await (await resp).arrayBuffer();
+                                return await WebAssembly.instantiate(source, importObject);
+                        };
+                }
+
+                const go = new Go();
+                let mod, inst;
+                WebAssembly.instantiateStreaming(fetch("%s"), go.importObject).then((result) => {
+                        mod = result.module;
+                        inst = result.instance;
+                        document.getElementById("runButton").disabled = false;
+                }).catch((err) => {
+                        console.error(err);
+                });
+
+                async function run() {
+                        console.clear();
+                        await go.run(inst);
+                        inst = await WebAssembly.instantiate(mod, go.importObject); // reset instance
+                }
+        </script>
+
+        <button onClick="run();" id="runButton" disabled>Run</button>
+%s
+</body>
+
+</html>
+`
+
+// reloadScript is injected into indexHTML when Example.Reload is set, so
+// that browser tabs left open on an example automatically refresh once
+// gen-p5's "serve" subcommand has rebuilt its WASM binary.
+const reloadScript = `
+        <script>
+                new EventSource("/events").onmessage = function(e) {
+                        if (e.data === "reload") {
+                                window.location.reload();
+                        }
+                };
+        </script>
+`
+
+const rootHeader = `
+<!doctype html>
+<html>
+<head>
+        <meta charset="utf-8">
+        <title>Go-P5</title>
+</head>
+
+<body>
+<h2>Welcome to the Go-P5 examples page (version=%s)</h2>
+This page shows a few <code>go-p5</code> examples, compiled to <code>WASM</code>.
+
+<ul>
+`
+
+const rootFooter = `
+</ul>
+</body>
+
+</html>
+`
@@ -0,0 +1,68 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Feed emits an Atom feed and a sitemap.xml listing every example, so the
+// examples site can be indexed and subscribed to.
+type Feed struct{}
+
+func (Feed) Name() string { return "feed" }
+
+func (Feed) Render(site Site) (map[string][]byte, error) {
+	updated := site.CommitDate.UTC().Format(time.RFC3339)
+
+	entries := new(strings.Builder)
+	urls := new(strings.Builder)
+	for _, ex := range site.Examples {
+		link := "https://go-p5.github.io/example/" + ex.Pkg + "/index.html"
+
+		fmt.Fprintf(entries, atomEntry, ex.Pkg, link, link, updated, link)
+		fmt.Fprintf(urls, sitemapURL, link, updated)
+	}
+
+	atom := fmt.Sprintf(atomFeed, updated, entries.String())
+	sitemap := fmt.Sprintf(sitemapXML, urls.String())
+
+	return map[string][]byte{
+		"feed.xml":    []byte(atom),
+		"sitemap.xml": []byte(sitemap),
+	}, nil
+}
+
+const atomFeed = `<?xml version="1.0" encoding="utf-8"?>
+<feed xmlns="http://www.w3.org/2005/Atom">
+        <title>Go-P5 examples</title>
+        <link href="https://go-p5.github.io/"/>
+        <id>https://go-p5.github.io/</id>
+        <updated>%s</updated>
+%s</feed>
+`
+
+const atomEntry = `        <entry>
+                <title>%s</title>
+                <link href=%q/>
+                <id>%s</id>
+                <updated>%s</updated>
+                <summary>go-p5 example, rendered to WASM</summary>
+                <content src=%q/>
+        </entry>
+`
+
+const sitemapXML = `<?xml version="1.0" encoding="utf-8"?>
+<urlset xmlns="http://www.sitemaps.org/schemas/sitemap/0.9">
+%s</urlset>
+`
+
+const sitemapURL = `        <url>
+                <loc>%s</loc>
+                <lastmod>%s</lastmod>
+        </url>
+`
@@ -0,0 +1,75 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"go/scanner"
+	"go/token"
+	"html"
+	"strings"
+)
+
+// highlightGo renders Go source as HTML, wrapping each token in a <span>
+// classed by token kind so a stylesheet can colorize it. Scanner errors
+// (e.g. a file mid-edit in the live editor) do not stop highlighting: the
+// scanner resumes after them and the malformed token is still wrapped. Only
+// a token whose offset falls before the previous token's end or past the end
+// of src ends highlighting, and the rest of the file is then written as plain
+// escaped text.
+func highlightGo(src string) string {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+
+	var s scanner.Scanner
+	s.Init(file, []byte(src), nil, scanner.ScanComments)
+
+	out := new(strings.Builder)
+	last := 0
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+
+		offset := fset.Position(pos).Offset
+		if offset < last || offset > len(src) {
+			break
+		}
+		out.WriteString(html.EscapeString(src[last:offset]))
+
+		text := lit
+		if text == "" {
+			text = tok.String()
+		}
+		out.WriteString(`<span class="tok-` + tokenClass(tok) + `">`)
+		out.WriteString(html.EscapeString(text))
+		out.WriteString(`</span>`)
+
+		last = offset + len(text)
+	}
+	if last < len(src) {
+		out.WriteString(html.EscapeString(src[last:]))
+	}
+	return out.String()
+}
+
+func tokenClass(tok token.Token) string {
+	switch {
+	case tok == token.COMMENT:
+		return "comment"
+	case tok == token.STRING || tok == token.CHAR:
+		return "string"
+	case tok == token.INT || tok == token.FLOAT || tok == token.IMAG:
+		return "number"
+	case tok.IsKeyword():
+		return "keyword"
+	case tok.IsOperator():
+		return "operator"
+	case tok == token.IDENT:
+		return "ident"
+	default:
+		return "other"
+	}
+}
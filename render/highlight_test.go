@@ -0,0 +1,20 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHighlightGoEscapesHTML(t *testing.T) {
+	got := highlightGo(`const s = "<script>"`)
+	if want := "&lt;script&gt;"; !strings.Contains(got, want) {
+		t.Fatalf("highlightGo output %q does not contain escaped %q", got, want)
+	}
+	if strings.Contains(got, "<script>") {
+		t.Fatalf("highlightGo output %q leaks unescaped %q", got, "<script>")
+	}
+}
@@ -0,0 +1,53 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package render generates the static pages gen-p5 publishes for each
+// go-p5/p5 example, decoupling the page layout from the build pipeline that
+// drives go-p5/p5 checkouts and WASM compilation.
+package render
+
+import "time"
+
+// SourceFile is a single Go source file belonging to an Example, as found
+// under p5/example/<pkg>.
+type SourceFile struct {
+	Name    string
+	Content string
+}
+
+// Example describes one go-p5/p5 example to be rendered.
+type Example struct {
+	Pkg              string       // package name, e.g. "mandelbrot"
+	Title            string       // page title, e.g. "Go-P5: mandelbrot"
+	WASMSrc          string       // URL the compiled WASM binary is served from
+	GoVersions       []string     // every Go toolchain configured for the run; a selector is shown when more than one
+	CurrentGoVersion string       // which of GoVersions this page was built with
+	Reload           bool         // inject the "serve" subcommand's live-reload client script
+	Sources          []SourceFile // Go source files making up the example
+}
+
+// Site is the full set of examples gen-p5 publishes for a single revision
+// of go-p5/p5.
+type Site struct {
+	Revision   string
+	CommitDate time.Time
+	Examples   []Example
+}
+
+// Renderer turns a Site into the set of files gen-p5 should write, keyed by
+// path relative to the site's output root.
+type Renderer interface {
+	// Name identifies the renderer, as passed to the "-renderer" flag.
+	Name() string
+	// Render returns the files to write for site, keyed by output path.
+	Render(site Site) (map[string][]byte, error)
+}
+
+// ByName is the registry of renderers gen-p5 knows how to produce a site
+// with, keyed by the same string clients pass to "-renderer".
+var ByName = map[string]Renderer{
+	"basic": Basic{},
+	"rich":  Rich{},
+	"feed":  Feed{},
+}
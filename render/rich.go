@@ -0,0 +1,179 @@
+// Copyright ©2021 The go-p5 Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package render
+
+import (
+	"fmt"
+	"html"
+	"path"
+	"strings"
+)
+
+// Rich is a godoc-static-style renderer: a root page with a sidebar listing
+// every example, and per-example pages showing the running WASM sketch next
+// to its Go source.
+type Rich struct{}
+
+func (Rich) Name() string { return "rich" }
+
+func (r Rich) Render(site Site) (map[string][]byte, error) {
+	out := make(map[string][]byte)
+
+	sidebar := new(strings.Builder)
+	for _, ex := range site.Examples {
+		fmt.Fprintf(sidebar, "<li><a href=%q>%s</a></li>\n", "/rich/example/"+ex.Pkg+"/index.html", ex.Pkg)
+		out[path.Join("rich", "example", ex.Pkg, "index.html")] = []byte(r.renderExample(ex))
+	}
+
+	out[path.Join("rich", "index.html")] = []byte(fmt.Sprintf(richRootHTML, site.Revision, sidebar.String()))
+	return out, nil
+}
+
+func (Rich) renderExample(ex Example) string {
+	sidebar := new(strings.Builder)
+	for _, src := range ex.Sources {
+		fmt.Fprintf(sidebar, "<li><a href=\"#%s\">%s</a></li>\n", src.Name, src.Name)
+	}
+
+	sources := new(strings.Builder)
+	for _, src := range ex.Sources {
+		fmt.Fprintf(sources, "<h3 id=%q>%s</h3>\n<pre><code>%s</code></pre>\n",
+			src.Name, src.Name, highlightGo(src.Content))
+	}
+
+	return fmt.Sprintf(richExampleHTML,
+		ex.Title, sidebar.String(), ex.WASMSrc, extras(ex, "/rich/example/"+ex.Pkg),
+		editorSection(ex), sources.String())
+}
+
+// editorSection renders the live editor and "Compile & Run" button for
+// examples built by the "serve" subcommand, which exposes the /build
+// endpoint it POSTs to. Statically generated sites have nothing to POST to,
+// so the section is omitted there.
+func editorSection(ex Example) string {
+	if !ex.Reload || len(ex.Sources) == 0 {
+		return ""
+	}
+	file := ex.Sources[0]
+	return fmt.Sprintf(editorHTML, html.EscapeString(file.Content), ex.Pkg, file.Name)
+}
+
+const richRootHTML = `
+<!doctype html>
+<html>
+<head>
+        <meta charset="utf-8">
+        <title>Go-P5 examples (version=%s)</title>
+</head>
+
+<body>
+<div class="layout">
+        <nav class="sidebar">
+                <h3>Examples</h3>
+                <ul>
+%s                </ul>
+        </nav>
+        <main>
+                <h2>Welcome to the Go-P5 examples page</h2>
+                Pick an example from the sidebar to see it running, alongside its source.
+        </main>
+</div>
+</body>
+
+</html>
+`
+
+const richExampleHTML = `
+<!doctype html>
+<html>
+
+<head>
+        <meta charset="utf-8">
+        <title>%s</title>
+</head>
+
+<body>
+<div class="layout">
+        <nav class="sidebar">
+                <h3>Source</h3>
+                <ul>
+%s                </ul>
+        </nav>
+        <main>
+		<script src="https://go-p5.github.io/assets/wasm_exec.js"></script>
+        <script>
+                if (!WebAssembly.instantiateStreaming) { // polyfill
+                        WebAssembly.instantiateStreaming = async (resp, importObject) => {
+                                const source = await (await resp).arrayBuffer();
+                                return await WebAssembly.instantiate(source, importObject);
+                        };
+                }
+
+                const go = new Go();
+                let mod, inst;
+                WebAssembly.instantiateStreaming(fetch("%s"), go.importObject).then((result) => {
+                        mod = result.module;
+                        inst = result.instance;
+                        document.getElementById("runButton").disabled = false;
+                }).catch((err) => {
+                        console.error(err);
+                });
+
+                async function run() {
+                        console.clear();
+                        await go.run(inst);
+                        inst = await WebAssembly.instantiate(mod, go.importObject); // reset instance
+                }
+        </script>
+
+        <button onClick="run();" id="runButton" disabled>Run</button>
+%s
+%s
+        <section class="source">
+%s        </section>
+        </main>
+</div>
+</body>
+
+</html>
+`
+
+const editorHTML = `
+        <section class="editor">
+                <h3>Edit &amp; run</h3>
+                <textarea id="editor" spellcheck="false" rows="20" cols="80">%s</textarea>
+                <br>
+                <button onClick="compileAndRun();" id="compileButton">Compile &amp; Run</button>
+                <pre id="buildOutput"></pre>
+                <script>
+                        async function compileAndRun() {
+                                const btn = document.getElementById("compileButton");
+                                const out = document.getElementById("buildOutput");
+                                btn.disabled = true;
+                                out.textContent = "";
+                                try {
+                                        const resp = await fetch("/build?pkg=%s&file=%s", {
+                                                method: "POST",
+                                                body: document.getElementById("editor").value,
+                                        });
+                                        if (!resp.ok) {
+                                                out.textContent = await resp.text();
+                                                return;
+                                        }
+                                        const buf = await resp.arrayBuffer();
+                                        const result = await WebAssembly.instantiate(buf, go.importObject);
+                                        mod = result.module;
+                                        inst = result.instance;
+                                        document.getElementById("runButton").disabled = false;
+                                        run();
+                                } catch (err) {
+                                        out.textContent = String(err);
+                                } finally {
+                                        btn.disabled = false;
+                                }
+                        }
+                </script>
+        </section>
+`